@@ -0,0 +1,182 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// caInfoBundle wraps the parsed CA certificate and key for this mount so
+// that callers (CRL/OCSP/issuance paths) don't each have to fetch and parse
+// config/ca_bundle themselves.
+type caInfoBundle struct {
+	*certutil.ParsedCertBundle
+}
+
+func fetchCAInfo(req *logical.Request) (*caInfoBundle, error) {
+	entry, err := req.Storage.Get("config/ca_bundle")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch local CA certificate/key: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var bundle certutil.CertBundle
+	if err := entry.DecodeJSON(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode local CA certificate/key: %v", err)
+	}
+
+	parsedBundle, err := bundle.ToParsedCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse local CA certificate/key: %v", err)
+	}
+
+	return &caInfoBundle{parsedBundle}, nil
+}
+
+// revocationEntry is the storage representation of a single revoked
+// certificate, keyed under "revoked/<normalized serial>". It is the single
+// source of truth consumed by both the CRL builder and the OCSP responder.
+type revocationEntry struct {
+	CertificateBytes []byte `json:"certificate"`
+	RevocationTime   int64  `json:"revocation_time"`
+
+	// Reason is the free-form revocation reason carried alongside the
+	// revocation date in an OpenSSL index.txt "R" line (e.g.
+	// "keyCompromise"), preserved so an export round-trips it. Empty when
+	// the revocation did not come from an index.txt import.
+	Reason string `json:"reason,omitempty"`
+
+	// NotAfterTime is the certificate expiry, in case this revocation was
+	// imported from an index.txt line whose serial has no corresponding
+	// "certs/" entry in this mount and so no other record of its expiry.
+	NotAfterTime int64 `json:"not_after_time,omitempty"`
+
+	// Subject is the certificate's subject DN, in case this revocation was
+	// imported from an index.txt line whose serial has no corresponding
+	// "certs/" entry in this mount - the normal case for a legacy "openssl
+	// ca" migration, since Vault never issued those certs itself.
+	Subject string `json:"subject,omitempty"`
+}
+
+func fetchRevocationEntry(req *logical.Request, serial string) (*revocationEntry, error) {
+	entry, err := req.Storage.Get("revoked/" + serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var revInfo revocationEntry
+	if err := entry.DecodeJSON(&revInfo); err != nil {
+		return nil, fmt.Errorf("failed to decode revocation entry for serial %s: %v", serial, err)
+	}
+
+	return &revInfo, nil
+}
+
+func fetchCertEntry(req *logical.Request, serial string) (*x509.Certificate, error) {
+	entry, err := req.Storage.Get("certs/" + serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	raw := entry.Value
+	if block, _ := pem.Decode(entry.Value); block != nil {
+		raw = block.Bytes
+	}
+
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored certificate for serial %s: %v", serial, err)
+	}
+
+	return cert, nil
+}
+
+// fetchOCSPDelegateBundle returns the delegated OCSP responder key/cert
+// configured for this mount, if any. Absence is not an error: callers fall
+// back to signing with the CA key directly.
+func fetchOCSPDelegateBundle(req *logical.Request) (*caInfoBundle, error) {
+	entry, err := req.Storage.Get("config/ocsp_delegate_bundle")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch delegated OCSP responder certificate/key: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var bundle certutil.CertBundle
+	if err := entry.DecodeJSON(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode delegated OCSP responder certificate/key: %v", err)
+	}
+
+	parsedBundle, err := bundle.ToParsedCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse delegated OCSP responder certificate/key: %v", err)
+	}
+
+	return &caInfoBundle{parsedBundle}, nil
+}
+
+// normalizeSerialForStorage turns a colon-separated or bare hex serial into
+// the hyphenated form used as the storage key suffix for "certs/" and
+// "revoked/" entries.
+func normalizeSerialForStorage(serial string) string {
+	serial = strings.Replace(serial, ":", "-", -1)
+
+	var hexPart string
+	if strings.Contains(serial, "-") {
+		return serial
+	}
+	hexPart = serial
+
+	var buf bytes.Buffer
+	for i := 0; i < len(hexPart); i += 2 {
+		if i > 0 {
+			buf.WriteString("-")
+		}
+		end := i + 2
+		if end > len(hexPart) {
+			end = len(hexPart)
+		}
+		buf.WriteString(hexPart[i:end])
+	}
+	return buf.String()
+}
+
+// subjectPublicKeyInfoASN1 mirrors the SubjectPublicKeyInfo ASN.1 sequence
+// so we can recover the raw subjectPublicKey BIT STRING content octets,
+// rather than hashing the whole AlgorithmIdentifier + BIT STRING blob.
+type subjectPublicKeyInfoASN1 struct {
+	Algorithm        pkix.AlgorithmIdentifier
+	SubjectPublicKey asn1.BitString
+}
+
+// hashIssuerKey returns the SHA-1 hash used as the OCSP IssuerKeyHash per
+// RFC 6960 appendix A.1: the hash of the content octets of the
+// subjectPublicKey BIT STRING, excluding the tag, length, and
+// number-of-unused-bits octet.
+func hashIssuerKey(cert *x509.Certificate) []byte {
+	var spki subjectPublicKeyInfoASN1
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		h := sha1.Sum(cert.RawSubjectPublicKeyInfo)
+		return h[:]
+	}
+
+	h := sha1.Sum(spki.SubjectPublicKey.RightAlign())
+	return h[:]
+}
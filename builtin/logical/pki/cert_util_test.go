@@ -0,0 +1,83 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func selfSignedTestCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+	return selfSignedTestCertNotAfter(t, time.Now().Add(time.Hour))
+}
+
+// selfSignedTestCertNotAfter is selfSignedTestCert with the encoded expiry
+// set to notAfter, valid for the hour before it.
+func selfSignedTestCertNotAfter(t *testing.T, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return cert
+}
+
+func TestHashIssuerKey_MatchesBitStringContentOnly(t *testing.T) {
+	cert := selfSignedTestCert(t)
+
+	var spki subjectPublicKeyInfoASN1
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		t.Fatalf("failed to unmarshal SubjectPublicKeyInfo: %v", err)
+	}
+	want := sha1.Sum(spki.SubjectPublicKey.RightAlign())
+
+	got := hashIssuerKey(cert)
+	if string(got) != string(want[:]) {
+		t.Fatalf("hashIssuerKey() = %x, want %x", got, want)
+	}
+
+	// Guard against a regression back to hashing the whole SPKI blob, which
+	// would never match a conformant OCSP client's IssuerKeyHash.
+	wholeBlob := sha1.Sum(cert.RawSubjectPublicKeyInfo)
+	if string(got) == string(wholeBlob[:]) {
+		t.Fatalf("hashIssuerKey() must not equal the hash of the full SubjectPublicKeyInfo blob")
+	}
+}
+
+func TestNormalizeSerialForStorage_EvenLengthPerByte(t *testing.T) {
+	// A serial whose leading byte is < 0x10 is the case that broke when the
+	// hex string came from big.Int formatting instead of per-byte formatting.
+	serial := big.NewInt(0x0aff)
+
+	normalized := normalizeSerialForStorage(fmt.Sprintf("%x", serial.Bytes()))
+	if normalized != "0a-ff" {
+		t.Fatalf("normalizeSerialForStorage(%x) = %q, want %q", serial, normalized, "0a-ff")
+	}
+}
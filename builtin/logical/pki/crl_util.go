@@ -0,0 +1,286 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	crlNumberStorageKey = "crl_number"
+	fullCRLStorageKey   = "crl"
+	deltaCRLStorageKey  = "delta-crl"
+
+	// deltaCRLIndicatorOID is id-ce-deltaCRLIndicator, RFC 5280 5.2.4.
+	deltaCRLIndicatorOID = "2.5.29.27"
+)
+
+// crlNumberEntry tracks the monotonically increasing CRL number along with
+// the number and issuance time of the most recent full CRL, so the delta
+// CRL rebuild knows both the DeltaCRLIndicator value to assert and the
+// cutoff for "revoked since the last full CRL".
+type crlNumberEntry struct {
+	CRLNumber     int64 `json:"crl_number"`
+	BaseCRLNumber int64 `json:"base_crl_number"`
+	BaseCRLTime   int64 `json:"base_crl_time"`
+}
+
+func getCRLNumbers(req *logical.Request) (*crlNumberEntry, error) {
+	entry, err := req.Storage.Get(crlNumberStorageKey)
+	if err != nil {
+		return nil, err
+	}
+	numbers := &crlNumberEntry{}
+	if entry == nil {
+		return numbers, nil
+	}
+	if err := entry.DecodeJSON(numbers); err != nil {
+		return nil, err
+	}
+	return numbers, nil
+}
+
+func putCRLNumbers(req *logical.Request, numbers *crlNumberEntry) error {
+	entry, err := logical.StorageEntryJSON(crlNumberStorageKey, numbers)
+	if err != nil {
+		return err
+	}
+	return req.Storage.Put(entry)
+}
+
+// revokedCertList walks the "revoked/" storage prefix - the same store the
+// OCSP responder consults - and returns the entries with a revocation time
+// at or after sinceUnix (sinceUnix == 0 means all of them).
+func revokedCertList(req *logical.Request, sinceUnix int64) ([]pkix.RevokedCertificate, error) {
+	serials, err := req.Storage.List("revoked/")
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []pkix.RevokedCertificate
+	for _, serial := range serials {
+		revInfo, err := fetchRevocationEntry(req, serial)
+		if err != nil {
+			return nil, err
+		}
+		if revInfo == nil || revInfo.RevocationTime < sinceUnix {
+			continue
+		}
+
+		serialHex := strings.Replace(serial, "-", "", -1)
+		serialNumber := new(big.Int)
+		if _, ok := serialNumber.SetString(serialHex, 16); !ok {
+			return nil, fmt.Errorf("failed to parse stored serial %q", serial)
+		}
+
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   serialNumber,
+			RevocationTime: time.Unix(revInfo.RevocationTime, 0),
+		})
+	}
+
+	return revoked, nil
+}
+
+// caSigner returns the CA's private key as a crypto.Signer, as required by
+// x509.CreateRevocationList.
+func caSigner(caBundle *caInfoBundle) (crypto.Signer, error) {
+	signer, ok := caBundle.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("CA key does not support signing")
+	}
+	return signer, nil
+}
+
+// freshestCRLExtension builds the id-ce-freshestCRL (RFC 5280 5.2.6)
+// extension from the mount's configured Freshest CRL URLs, for the
+// certificate issuance path to attach to issued certificates whenever
+// freshest_crl is configured, mirroring how that path already attaches
+// CRLDistributionPoints. NOTE: the issuance path itself is not part of
+// this change (it lives outside this series); wiring this in is a
+// follow-up once that path is touched.
+func freshestCRLExtension(urls *urlEntries) (pkix.Extension, bool) {
+	if urls == nil || len(urls.FreshestCRL) == 0 {
+		return pkix.Extension{}, false
+	}
+
+	var names []asn1.RawValue
+	for _, u := range urls.FreshestCRL {
+		names = append(names, asn1.RawValue{
+			Class: asn1.ClassContextSpecific,
+			Tag:   6,
+			Bytes: []byte(u),
+		})
+	}
+
+	type distributionPoint struct {
+		Name []asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+
+	value, err := asn1.Marshal([]distributionPoint{{Name: names}})
+	if err != nil {
+		return pkix.Extension{}, false
+	}
+
+	return pkix.Extension{
+		// id-ce-freshestCRL
+		Id:    asn1.ObjectIdentifier{2, 5, 29, 46},
+		Value: value,
+	}, true
+}
+
+// buildCRL rebuilds and persists the full CRL under "crl", snapshots the new
+// CRL number as the delta CRL baseline, and returns the encoded CRL.
+func buildCRL(b *backend, req *logical.Request) ([]byte, error) {
+	caBundle, err := fetchCAInfo(req)
+	if err != nil {
+		return nil, err
+	}
+	if caBundle == nil {
+		return nil, fmt.Errorf("backend has no CA configured, cannot build CRL")
+	}
+
+	crlConfig, err := getCRLConfig(req)
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := time.ParseDuration(crlConfig.Expiry)
+	if err != nil {
+		expiry = 72 * time.Hour
+	}
+
+	numbers, err := getCRLNumbers(req)
+	if err != nil {
+		return nil, err
+	}
+	numbers.CRLNumber++
+
+	revoked, err := revokedCertList(req, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := caSigner(caBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use x509.CreateRevocationList (rather than the legacy
+	// x509.Certificate.CreateCRL, which cannot add extensions) so the full
+	// CRL carries a CRL Number per RFC 5280 5.2.3 - required for a client to
+	// validate that a delta CRL's DeltaCRLIndicator actually corresponds to
+	// the full CRL it holds.
+	template := &x509.RevocationList{
+		RevokedCertificates: revoked,
+		Number:              big.NewInt(numbers.CRLNumber),
+		ThisUpdate:          time.Now(),
+		NextUpdate:          time.Now().Add(expiry),
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, caBundle.Certificate, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CRL: %v", err)
+	}
+
+	if err := req.Storage.Put(&logical.StorageEntry{Key: fullCRLStorageKey, Value: der}); err != nil {
+		return nil, err
+	}
+
+	numbers.BaseCRLNumber = numbers.CRLNumber
+	numbers.BaseCRLTime = time.Now().Unix()
+	if err := putCRLNumbers(req, numbers); err != nil {
+		return nil, err
+	}
+
+	if crlConfig.DeltaRebuildInterval != "" {
+		if _, err := buildDeltaCRL(b, req); err != nil {
+			return nil, fmt.Errorf("rebuilt full CRL but failed to rebuild delta CRL: %v", err)
+		}
+	}
+
+	return der, nil
+}
+
+// buildDeltaCRL rebuilds and persists the delta CRL under "delta-crl",
+// containing only the revocations recorded since the last full CRL build,
+// with the DeltaCRLIndicator critical extension set to the base CRL number.
+func buildDeltaCRL(b *backend, req *logical.Request) ([]byte, error) {
+	caBundle, err := fetchCAInfo(req)
+	if err != nil {
+		return nil, err
+	}
+	if caBundle == nil {
+		return nil, fmt.Errorf("backend has no CA configured, cannot build delta CRL")
+	}
+
+	crlConfig, err := getCRLConfig(req)
+	if err != nil {
+		return nil, err
+	}
+	if crlConfig.DeltaRebuildInterval == "" {
+		return nil, fmt.Errorf("delta CRLs are disabled for this mount")
+	}
+
+	deltaInterval, err := time.ParseDuration(crlConfig.DeltaRebuildInterval)
+	if err != nil {
+		deltaInterval = 15 * time.Minute
+	}
+
+	numbers, err := getCRLNumbers(req)
+	if err != nil {
+		return nil, err
+	}
+	numbers.CRLNumber++
+
+	revoked, err := revokedCertList(req, numbers.BaseCRLTime)
+	if err != nil {
+		return nil, err
+	}
+
+	indicator, err := asn1.Marshal(big.NewInt(numbers.BaseCRLNumber))
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := caSigner(caBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.RevocationList{
+		RevokedCertificates: revoked,
+		Number:              big.NewInt(numbers.CRLNumber),
+		ThisUpdate:          time.Now(),
+		NextUpdate:          time.Now().Add(deltaInterval),
+		ExtraExtensions: []pkix.Extension{
+			{
+				Id:       asn1.ObjectIdentifier{2, 5, 29, 27},
+				Critical: true,
+				Value:    indicator,
+			},
+		},
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, caBundle.Certificate, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delta CRL: %v", err)
+	}
+
+	if err := req.Storage.Put(&logical.StorageEntry{Key: deltaCRLStorageKey, Value: der}); err != nil {
+		return nil, err
+	}
+
+	if err := putCRLNumbers(req, numbers); err != nil {
+		return nil, err
+	}
+
+	return der, nil
+}
@@ -0,0 +1,142 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// seedCABundle generates a self-signed CA (CRL-signing capable, as
+// x509.CreateRevocationList requires) and stores it as this mount's
+// config/ca_bundle, the way buildCRL/buildDeltaCRL expect to find it.
+func seedCABundle(t *testing.T, storage logical.Storage) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal CA key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	bundle := certutil.CertBundle{
+		PrivateKeyType: "ec",
+		Certificate:    string(certPEM),
+		PrivateKey:     string(keyPEM),
+	}
+
+	entry, err := logical.StorageEntryJSON("config/ca_bundle", &bundle)
+	if err != nil {
+		t.Fatalf("failed to marshal config/ca_bundle: %v", err)
+	}
+	if err := storage.Put(entry); err != nil {
+		t.Fatalf("failed to seed config/ca_bundle: %v", err)
+	}
+}
+
+func TestBuildCRL_DeltaExcludesPreBaselineRevocations(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := &backend{}
+	req := &logical.Request{Storage: storage}
+
+	seedCABundle(t, storage)
+
+	// Revoke one serial, then build the full CRL - this becomes the delta
+	// baseline, so this revocation must NOT show up in the next delta CRL.
+	preBaseline := &revocationEntry{RevocationTime: time.Now().Add(-time.Hour).Unix()}
+	preEntry, err := logical.StorageEntryJSON("revoked/aa", preBaseline)
+	if err != nil {
+		t.Fatalf("failed to marshal revocation entry: %v", err)
+	}
+	if err := storage.Put(preEntry); err != nil {
+		t.Fatalf("failed to seed revoked/aa: %v", err)
+	}
+
+	if _, err := buildCRL(b, req); err != nil {
+		t.Fatalf("buildCRL() error = %v", err)
+	}
+
+	numbersAfterFull, err := getCRLNumbers(req)
+	if err != nil {
+		t.Fatalf("getCRLNumbers() error = %v", err)
+	}
+	if numbersAfterFull.CRLNumber != 2 {
+		// buildCRL's own rebuild plus the delta rebuild it triggers (since
+		// config/crl defaults delta_rebuild_interval to "15m") each bump the
+		// counter once.
+		t.Fatalf("CRLNumber after buildCRL() = %d, want 2", numbersAfterFull.CRLNumber)
+	}
+
+	// Revoke a second serial after the full CRL baseline, then rebuild the
+	// delta CRL directly.
+	postBaseline := &revocationEntry{RevocationTime: time.Now().Unix()}
+	postEntry, err := logical.StorageEntryJSON("revoked/bb", postBaseline)
+	if err != nil {
+		t.Fatalf("failed to marshal revocation entry: %v", err)
+	}
+	if err := storage.Put(postEntry); err != nil {
+		t.Fatalf("failed to seed revoked/bb: %v", err)
+	}
+
+	der, err := buildDeltaCRL(b, req)
+	if err != nil {
+		t.Fatalf("buildDeltaCRL() error = %v", err)
+	}
+
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("failed to parse delta CRL: %v", err)
+	}
+
+	var serials []string
+	for _, rc := range crl.RevokedCertificates {
+		serials = append(serials, rc.SerialNumber.String())
+	}
+	if len(serials) != 1 || serials[0] != big.NewInt(0xbb).String() {
+		t.Fatalf("delta CRL revoked serials = %v, want only the post-baseline serial (%s)",
+			serials, big.NewInt(0xbb).String())
+	}
+
+	numbersAfterDelta, err := getCRLNumbers(req)
+	if err != nil {
+		t.Fatalf("getCRLNumbers() error = %v", err)
+	}
+	if numbersAfterDelta.CRLNumber <= numbersAfterFull.CRLNumber {
+		t.Fatalf("CRLNumber did not increase across the delta rebuild: before=%d after=%d",
+			numbersAfterFull.CRLNumber, numbersAfterDelta.CRLNumber)
+	}
+	if crl.Number.Int64() != numbersAfterDelta.CRLNumber {
+		t.Fatalf("delta CRL's own Number = %d, want it to match the stored CRLNumber %d",
+			crl.Number.Int64(), numbersAfterDelta.CRLNumber)
+	}
+}
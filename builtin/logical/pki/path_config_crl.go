@@ -0,0 +1,131 @@
+package pki
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const crlConfigStorageKey = "config/crl"
+
+func pathConfigCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/crl",
+		Fields: map[string]*framework.FieldSchema{
+			"expiry": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "72h",
+				Description: `The amount of time, expressed as a duration string such as
+"72h", before the full CRL is next rebuilt`,
+			},
+
+			"delta_rebuild_interval": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "15m",
+				Description: `The amount of time, expressed as a duration string such as
+"15m", between rebuilds of the delta CRL. Set to "" to disable delta
+CRLs`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:  b.pathReadConfigCRL,
+			logical.WriteOperation: b.pathWriteConfigCRL,
+		},
+
+		HelpSynopsis:    pathConfigCRLHelpSyn,
+		HelpDescription: pathConfigCRLHelpDesc,
+	}
+}
+
+type crlConfigEntry struct {
+	Expiry               string `json:"expiry" structs:"expiry" mapstructure:"expiry"`
+	DeltaRebuildInterval string `json:"delta_rebuild_interval" structs:"delta_rebuild_interval" mapstructure:"delta_rebuild_interval"`
+}
+
+func getCRLConfig(req *logical.Request) (*crlConfigEntry, error) {
+	config := &crlConfigEntry{
+		Expiry:               "72h",
+		DeltaRebuildInterval: "15m",
+	}
+
+	entry, err := req.Storage.Get(crlConfigStorageKey)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (b *backend) pathReadConfigCRL(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getCRLConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: structs.New(config).Map(),
+	}, nil
+}
+
+func (b *backend) pathWriteConfigCRL(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getCRLConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiryRaw, ok := data.GetOk("expiry"); ok {
+		expiry := expiryRaw.(string)
+		if _, err := time.ParseDuration(expiry); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"invalid expiry %q: %s", expiry, err)), nil
+		}
+		config.Expiry = expiry
+	}
+
+	if intervalRaw, ok := data.GetOk("delta_rebuild_interval"); ok {
+		interval := intervalRaw.(string)
+		if interval != "" {
+			if _, err := time.ParseDuration(interval); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf(
+					"invalid delta_rebuild_interval %q: %s", interval, err)), nil
+			}
+		}
+		config.DeltaRebuildInterval = interval
+	}
+
+	entry, err := logical.StorageEntryJSON(crlConfigStorageKey, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigCRLHelpSyn = `
+Configure the CRL and delta CRL rebuild behavior for this mount.
+`
+
+const pathConfigCRLHelpDesc = `
+This path lets you configure the full-CRL rebuild interval (expiry) and,
+independently, the delta-CRL rebuild interval (delta_rebuild_interval).
+The delta CRL published at pki/delta-crl only contains revocations that
+occurred since the last full CRL rebuild, letting clients that already
+hold a recent full CRL avoid re-fetching the entire revocation list.
+Set delta_rebuild_interval to an empty string to disable delta CRL
+generation.
+`
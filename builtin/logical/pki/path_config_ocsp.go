@@ -0,0 +1,153 @@
+package pki
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const ocspConfigStorageKey = "config/ocsp"
+
+func pathConfigOCSP(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/ocsp",
+		Fields: map[string]*framework.FieldSchema{
+			"disable": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the OCSP responder mounted at pki/ocsp returns an
+unauthorized response for every request`,
+			},
+
+			"ocsp_expiry": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "24h",
+				Description: `Duration string such as "24h" placed on signed OCSP
+responses between thisUpdate and nextUpdate`,
+			},
+
+			"nonce_policy": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "echo",
+				Description: `How to handle the nonce request extension defined in RFC
+6960 appendix A.1: "echo" reflects the client nonce back in the
+response, "ignore" omits it from the response`,
+			},
+
+			"use_delegated_responder": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, sign OCSP responses with a delegated OCSP
+responder key/certificate (bearing the id-kp-OCSPSigning EKU) instead
+of signing directly with the CA key. If no delegated responder
+key/certificate is stored for this mount, the CA key is used`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:  b.pathReadConfigOCSP,
+			logical.WriteOperation: b.pathWriteConfigOCSP,
+		},
+
+		HelpSynopsis:    pathConfigOCSPHelpSyn,
+		HelpDescription: pathConfigOCSPHelpDesc,
+	}
+}
+
+type ocspConfigEntry struct {
+	Disable               bool   `json:"disable" structs:"disable" mapstructure:"disable"`
+	OcspExpiry            string `json:"ocsp_expiry" structs:"ocsp_expiry" mapstructure:"ocsp_expiry"`
+	NoncePolicy           string `json:"nonce_policy" structs:"nonce_policy" mapstructure:"nonce_policy"`
+	UseDelegatedResponder bool   `json:"use_delegated_responder" structs:"use_delegated_responder" mapstructure:"use_delegated_responder"`
+}
+
+func getOcspConfig(req *logical.Request) (*ocspConfigEntry, error) {
+	config := &ocspConfigEntry{
+		OcspExpiry:  "24h",
+		NoncePolicy: "echo",
+	}
+
+	entry, err := req.Storage.Get(ocspConfigStorageKey)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (b *backend) pathReadConfigOCSP(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getOcspConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: structs.New(config).Map(),
+	}, nil
+}
+
+func (b *backend) pathWriteConfigOCSP(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getOcspConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if disableRaw, ok := data.GetOk("disable"); ok {
+		config.Disable = disableRaw.(bool)
+	}
+
+	if expiryRaw, ok := data.GetOk("ocsp_expiry"); ok {
+		expiry := expiryRaw.(string)
+		if _, err := time.ParseDuration(expiry); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"invalid ocsp_expiry %q: %s", expiry, err)), nil
+		}
+		config.OcspExpiry = expiry
+	}
+
+	if policyRaw, ok := data.GetOk("nonce_policy"); ok {
+		policy := policyRaw.(string)
+		switch policy {
+		case "echo", "ignore":
+			config.NoncePolicy = policy
+		default:
+			return logical.ErrorResponse(fmt.Sprintf(
+				"invalid nonce_policy %q: must be \"echo\" or \"ignore\"", policy)), nil
+		}
+	}
+
+	if delegatedRaw, ok := data.GetOk("use_delegated_responder"); ok {
+		config.UseDelegatedResponder = delegatedRaw.(bool)
+	}
+
+	entry, err := logical.StorageEntryJSON(ocspConfigStorageKey, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigOCSPHelpSyn = `
+Configure the OCSP responder mounted at pki/ocsp.
+`
+
+const pathConfigOCSPHelpDesc = `
+This path configures how pki/ocsp answers OCSP requests: the validity
+window placed on signed responses, how the request nonce extension is
+handled, and whether responses are signed with the CA key directly or
+with a delegated OCSP responder key/certificate. Set disable to true to
+make the responder return unauthorized for every request.
+`
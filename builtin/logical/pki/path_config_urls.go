@@ -3,35 +3,20 @@ package pki
 import (
 	"fmt"
 	"net/url"
-	"strings"
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+// defaultIssuerRef is the issuer identifier used by the unscoped
+// config/urls path, so single-CA mounts keep working unchanged.
+const defaultIssuerRef = "default"
+
 func pathConfigURLs(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "config/urls",
-		Fields: map[string]*framework.FieldSchema{
-			"issuing_certificates": &framework.FieldSchema{
-				Type: framework.TypeString,
-				Description: `Comma-separated list of URLs to be used
-for the issuing certificate attribute`,
-			},
-
-			"crl_distribution_points": &framework.FieldSchema{
-				Type: framework.TypeString,
-				Description: `Comma-separated list of URLs to be used
-for the CRL distribution points attribute`,
-			},
-
-			"ocsp_servers": &framework.FieldSchema{
-				Type: framework.TypeString,
-				Description: `Comma-separated list of URLs to be used
-for the OCSP servers attribute`,
-			},
-		},
+		Fields:  urlFields(),
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.WriteOperation: b.pathWriteURL,
@@ -43,35 +28,154 @@ for the OCSP servers attribute`,
 	}
 }
 
-func validateURLs(urls []string) (string, error) {
+func urlFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"issuing_certificates": &framework.FieldSchema{
+			Type: framework.TypeCommaStringSlice,
+			Description: `List of URLs to be used for the issuing certificate
+attribute. Must be http:// or https:// URLs.`,
+		},
+
+		"crl_distribution_points": &framework.FieldSchema{
+			Type: framework.TypeCommaStringSlice,
+			Description: `List of URLs to be used for the CRL distribution
+points attribute. Must be http:// or https:// URLs.`,
+		},
+
+		"ocsp_servers": &framework.FieldSchema{
+			Type: framework.TypeCommaStringSlice,
+			Description: `List of URLs to be used for the OCSP servers
+attribute. Must be http:// URLs; OCSP over https is discouraged, as it
+creates a chicken-and-egg problem for clients validating the responder's
+own TLS certificate.`,
+		},
+
+		"delta_crl_distribution_points": &framework.FieldSchema{
+			Type: framework.TypeCommaStringSlice,
+			Description: `List of URLs to be used for the delta CRL
+distribution points attribute. Must be http:// or https:// URLs.`,
+		},
+
+		"freshest_crl": &framework.FieldSchema{
+			Type: framework.TypeCommaStringSlice,
+			Description: `List of URLs to be used for the Freshest CRL
+attribute. Must be http:// or https:// URLs.`,
+		},
+	}
+}
+
+// urlFieldSchemes maps each URL field to the URI schemes it accepts.
+// issuing_certificates and the CRL-related fields are consumed by AIA/CDP
+// aware clients per RFC 5280 4.2.2.1/4.2.1.13, which assume http(s); OCSP
+// over https is discouraged since validating the responder's own TLS
+// certificate could itself require an OCSP check.
+var urlFieldSchemes = map[string][]string{
+	"issuing_certificates":          {"http", "https"},
+	"crl_distribution_points":       {"http", "https"},
+	"delta_crl_distribution_points": {"http", "https"},
+	"freshest_crl":                  {"http", "https"},
+	"ocsp_servers":                  {"http"},
+}
+
+// validateURLs returns every URL in urls that is not a valid RFC 3986
+// request-URI or whose scheme is not in allowedSchemes, so callers can
+// report all of them at once instead of bailing on the first.
+func validateURLs(urls []string, allowedSchemes []string) []string {
+	var invalid []string
+
 	for _, curr := range urls {
-		if _, err := url.Parse(curr); err != nil {
-			return curr, err
+		parsed, err := url.ParseRequestURI(curr)
+		if err != nil {
+			invalid = append(invalid, curr)
+			continue
+		}
+
+		ok := false
+		for _, scheme := range allowedSchemes {
+			if parsed.Scheme == scheme {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			invalid = append(invalid, curr)
 		}
 	}
 
-	return "", nil
+	return invalid
+}
+
+func schemeList(schemes []string) string {
+	if len(schemes) == 1 {
+		return schemes[0]
+	}
+
+	out := schemes[0]
+	for _, s := range schemes[1:] {
+		out += "/" + s
+	}
+	return out
+}
+
+// urlsStorageKey returns the per-issuer storage key for a given issuer_ref,
+// defaulting an empty ref to defaultIssuerRef.
+func urlsStorageKey(issuerRef string) string {
+	if issuerRef == "" {
+		issuerRef = defaultIssuerRef
+	}
+	return "urls/" + issuerRef
 }
 
-func getURLs(req *logical.Request) (*urlEntries, error) {
-	entry, err := req.Storage.Get("urls")
+// getURLs fetches the URL entries for a specific issuer. For the default
+// issuer it transparently migrates the legacy single-issuer "urls" storage
+// entry, written before this mount supported more than one CA, to its new
+// per-issuer location on first read.
+func getURLs(req *logical.Request, issuerRef string) (*urlEntries, error) {
+	if issuerRef == "" {
+		issuerRef = defaultIssuerRef
+	}
+
+	entry, err := req.Storage.Get(urlsStorageKey(issuerRef))
 	if err != nil {
 		return nil, err
 	}
-	if entry == nil {
+	if entry != nil {
+		var entries urlEntries
+		if err := entry.DecodeJSON(&entries); err != nil {
+			return nil, err
+		}
+		return &entries, nil
+	}
+
+	if issuerRef != defaultIssuerRef {
+		return nil, nil
+	}
+
+	legacy, err := req.Storage.Get("urls")
+	if err != nil {
+		return nil, err
+	}
+	if legacy == nil {
 		return nil, nil
 	}
 
 	var entries urlEntries
-	if err := entry.DecodeJSON(&entries); err != nil {
+	if err := legacy.DecodeJSON(&entries); err != nil {
+		return nil, err
+	}
+
+	if err := writeURLs(req, defaultIssuerRef, &entries); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Delete("urls"); err != nil {
 		return nil, err
 	}
 
 	return &entries, nil
 }
 
-func writeURLs(req *logical.Request, entries *urlEntries) error {
-	entry, err := logical.StorageEntryJSON("urls", entries)
+func writeURLs(req *logical.Request, issuerRef string, entries *urlEntries) error {
+	entry, err := logical.StorageEntryJSON(urlsStorageKey(issuerRef), entries)
 	if err != nil {
 		return err
 	}
@@ -89,7 +193,16 @@ func writeURLs(req *logical.Request, entries *urlEntries) error {
 
 func (b *backend) pathReadURL(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	entries, err := getURLs(req)
+	return b.readURLsForIssuer(req, defaultIssuerRef)
+}
+
+func (b *backend) pathWriteURL(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.writeURLsForIssuer(req, data, defaultIssuerRef)
+}
+
+func (b *backend) readURLsForIssuer(req *logical.Request, issuerRef string) (*logical.Response, error) {
+	entries, err := getURLs(req, issuerRef)
 	if err != nil {
 		return nil, err
 	}
@@ -104,52 +217,61 @@ func (b *backend) pathReadURL(
 	return resp, nil
 }
 
-func (b *backend) pathWriteURL(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	entries, err := getURLs(req)
+func (b *backend) writeURLsForIssuer(
+	req *logical.Request, data *framework.FieldData, issuerRef string) (*logical.Response, error) {
+	entries, err := getURLs(req, issuerRef)
 	if err != nil {
 		return nil, err
 	}
 	if entries == nil {
 		entries = &urlEntries{
-			IssuingCertificates:   []string{},
-			CRLDistributionPoints: []string{},
-			OCSPServers:           []string{},
+			IssuingCertificates:        []string{},
+			CRLDistributionPoints:      []string{},
+			OCSPServers:                []string{},
+			DeltaCRLDistributionPoints: []string{},
+			FreshestCRL:                []string{},
 		}
 	}
 
-	if urlsInt, ok := data.GetOk("issuing_certificates"); ok {
-		splitURLs := strings.Split(urlsInt.(string), ",")
-		entries.IssuingCertificates = splitURLs
-		if badUrl, err := validateURLs(entries.CRLDistributionPoints); err != nil {
-			return logical.ErrorResponse(fmt.Sprintf(
-				"invalid URL found in issuing certificates; url is %s, error is %s", badUrl, err)), nil
-		}
+	fieldTargets := map[string]*[]string{
+		"issuing_certificates":          &entries.IssuingCertificates,
+		"crl_distribution_points":       &entries.CRLDistributionPoints,
+		"ocsp_servers":                  &entries.OCSPServers,
+		"delta_crl_distribution_points": &entries.DeltaCRLDistributionPoints,
+		"freshest_crl":                  &entries.FreshestCRL,
 	}
-	if urlsInt, ok := data.GetOk("crl_distribution_points"); ok {
-		splitURLs := strings.Split(urlsInt.(string), ",")
-		entries.CRLDistributionPoints = splitURLs
-		if badUrl, err := validateURLs(entries.CRLDistributionPoints); err != nil {
-			return logical.ErrorResponse(fmt.Sprintf(
-				"invalid URL found in CRL distribution points; url is %s, error is %s", badUrl, err)), nil
+
+	for _, field := range []string{
+		"issuing_certificates",
+		"crl_distribution_points",
+		"ocsp_servers",
+		"delta_crl_distribution_points",
+		"freshest_crl",
+	} {
+		urlsRaw, ok := data.GetOk(field)
+		if !ok {
+			continue
 		}
-	}
-	if urlsInt, ok := data.GetOk("ocsp_servers"); ok {
-		splitURLs := strings.Split(urlsInt.(string), ",")
-		entries.OCSPServers = splitURLs
-		if badUrl, err := validateURLs(entries.CRLDistributionPoints); err != nil {
+		urls := urlsRaw.([]string)
+
+		if invalid := validateURLs(urls, urlFieldSchemes[field]); len(invalid) > 0 {
 			return logical.ErrorResponse(fmt.Sprintf(
-				"invalid URL found in OCSP servers; url is %s, error is %s", badUrl, err)), nil
+				"invalid URL(s) found in %s, must be a valid %s URL: %s",
+				field, schemeList(urlFieldSchemes[field]), invalid)), nil
 		}
+
+		*fieldTargets[field] = urls
 	}
 
-	return nil, writeURLs(req, entries)
+	return nil, writeURLs(req, issuerRef, entries)
 }
 
 type urlEntries struct {
-	IssuingCertificates   []string `json:"issuing_certificates" structs:"issuing_certificates" mapstructure:"issuing_certificates"`
-	CRLDistributionPoints []string `json:"crl_distribution_points" structs:"crl_distribution_points" mapstructure:"crl_distribution_points"`
-	OCSPServers           []string `json:"ocsp_servers" structs:"ocsp_servers" mapstructure:"ocsp_servers"`
+	IssuingCertificates        []string `json:"issuing_certificates" structs:"issuing_certificates" mapstructure:"issuing_certificates"`
+	CRLDistributionPoints      []string `json:"crl_distribution_points" structs:"crl_distribution_points" mapstructure:"crl_distribution_points"`
+	OCSPServers                []string `json:"ocsp_servers" structs:"ocsp_servers" mapstructure:"ocsp_servers"`
+	DeltaCRLDistributionPoints []string `json:"delta_crl_distribution_points" structs:"delta_crl_distribution_points" mapstructure:"delta_crl_distribution_points"`
+	FreshestCRL                []string `json:"freshest_crl" structs:"freshest_crl" mapstructure:"freshest_crl"`
 }
 
 const pathConfigURLsHelpSyn = `
@@ -157,11 +279,27 @@ Set the URLs for the issuing CA, CRL distribution points, and OCSP servers.
 `
 
 const pathConfigURLsHelpDesc = `
-This path allows you to set the issuing CA, CRL distribution points, and
-OCSP server URLs that will be encoded into issued certificates. If these
+This path allows you to set the issuing CA, CRL distribution points, delta
+CRL distribution points, Freshest CRL, and OCSP server URLs that will be
+encoded into certificates issued by this mount's default issuer. If these
 values are not set, no such information will be encoded in the issued
 certificates. To delete URLs, simply re-set the appropriate value with an
 empty string.
 
 Multiple URLs can be specified for each type; use commas to separate them.
-`
\ No newline at end of file
+issuing_certificates, crl_distribution_points, delta_crl_distribution_points,
+and freshest_crl require http:// or https:// URLs; ocsp_servers requires
+http:// URLs only, since OCSP over https is discouraged.
+delta_crl_distribution_points and freshest_crl are stored and returned by
+this path, but the Freshest CRL extension is not added to issued
+certificates.
+
+On a mount hosting more than one CA, use config/urls/<issuer_ref> to set
+these URLs per issuer instead. NOTE: the certificate signing path does not
+yet consult per-issuer URLs - every leaf is still encoded using this
+path's default-issuer entry regardless of which issuer actually signs
+it - so per-issuer URLs configured there have no effect until that path
+is updated to be issuer-aware.
+
+See config/crl to configure the full-CRL and delta-CRL rebuild intervals.
+`
@@ -0,0 +1,77 @@
+package pki
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// pathConfigURLsByIssuer exposes config/urls/<issuer_ref> so a mount hosting
+// multiple root/intermediate CAs can advertise different AIA, CRL, and OCSP
+// URLs per issuer. This only adds the storage/read/write plumbing;
+// selectURLEntriesForIssuer is NOT yet called from the certificate signing
+// path (that path lives outside this series) - wiring it in, so a leaf
+// actually picks up its issuer's URLs instead of always using the default
+// issuer's, is a follow-up.
+func pathConfigURLsByIssuer(b *backend) *framework.Path {
+	fields := urlFields()
+	fields["issuer_ref"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Reference (name or ID) to the issuer these URLs apply to`,
+	}
+
+	return &framework.Path{
+		Pattern: "config/urls/" + framework.GenericNameRegex("issuer_ref"),
+		Fields:  fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.WriteOperation: b.pathWriteURLByIssuer,
+			logical.ReadOperation:  b.pathReadURLByIssuer,
+		},
+
+		HelpSynopsis:    pathConfigURLsByIssuerHelpSyn,
+		HelpDescription: pathConfigURLsByIssuerHelpDesc,
+	}
+}
+
+func (b *backend) pathReadURLByIssuer(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.readURLsForIssuer(req, data.Get("issuer_ref").(string))
+}
+
+func (b *backend) pathWriteURLByIssuer(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.writeURLsForIssuer(req, data, data.Get("issuer_ref").(string))
+}
+
+// selectURLEntriesForIssuer picks the URL entries for the issuer that signs
+// a given leaf, falling back to the default issuer's entries if the issuer
+// has none of its own configured. NOT YET CALLED: the signing path needs to
+// call this once it is updated to be issuer-aware; until then every leaf is
+// still encoded using the default issuer's config/urls entry regardless of
+// which issuer actually signed it.
+func selectURLEntriesForIssuer(req *logical.Request, issuerRef string) (*urlEntries, error) {
+	entries, err := getURLs(req, issuerRef)
+	if err != nil {
+		return nil, err
+	}
+	if entries != nil {
+		return entries, nil
+	}
+
+	return getURLs(req, defaultIssuerRef)
+}
+
+const pathConfigURLsByIssuerHelpSyn = `
+Set the URLs for the issuing CA, CRL distribution points, and OCSP servers
+for a specific issuer on this mount.
+`
+
+const pathConfigURLsByIssuerHelpDesc = `
+This path behaves like config/urls, but scopes the issuing certificate,
+CRL distribution point, delta CRL distribution point, Freshest CRL, and
+OCSP server URLs to a single issuer identified by issuer_ref. Use this on
+mounts hosting more than one root/intermediate CA to store a separate set
+of AIA/CRL/OCSP endpoints per issuer. Certificate signing does not read
+these per-issuer entries: every leaf is encoded using the default
+issuer's config/urls entry, whichever issuer signs it.
+`
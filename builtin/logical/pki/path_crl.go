@@ -0,0 +1,51 @@
+package pki
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// pathDeltaCRL serves the delta CRL built by buildDeltaCRL alongside the
+// full CRL served from pki/crl, letting clients that already hold a recent
+// full CRL fetch only the revocations that happened since.
+func pathDeltaCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "delta-crl",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchDeltaCRL,
+		},
+
+		HelpSynopsis:    pathDeltaCRLHelpSyn,
+		HelpDescription: pathDeltaCRLHelpDesc,
+	}
+}
+
+func (b *backend) pathFetchDeltaCRL(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := req.Storage.Get(deltaCRLStorageKey)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/pkix-crl",
+			logical.HTTPRawBody:     entry.Value,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+const pathDeltaCRLHelpSyn = `
+Fetch the current delta CRL.
+`
+
+const pathDeltaCRLHelpDesc = `
+This path returns the current delta CRL in DER form, containing only the
+revocations recorded since the last full CRL rebuild at pki/crl. See
+config/crl to configure the full and delta CRL rebuild intervals.
+`
@@ -0,0 +1,245 @@
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// ocspNonceOID is id-pkix-ocsp-nonce (RFC 8954).
+var ocspNonceOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+// tbsRequestASN1 and ocspRequestASN1 mirror just enough of the ASN.1
+// OCSPRequest structure (RFC 6960 appendix A.1) to recover requestExtensions.
+// golang.org/x/crypto/ocsp's Request (what ocsp.ParseRequest returns) only
+// surfaces HashAlgorithm/IssuerNameHash/IssuerKeyHash/SerialNumber and drops
+// the client's raw extensions entirely, so the nonce has to be pulled out of
+// rawReq by hand.
+type tbsRequestASN1 struct {
+	Version           int              `asn1:"optional,explicit,default:0,tag:0"`
+	RequestorName     asn1.RawValue    `asn1:"optional,explicit,tag:1"`
+	RequestList       []asn1.RawValue
+	RequestExtensions []pkix.Extension `asn1:"optional,explicit,tag:2"`
+}
+
+type ocspRequestASN1 struct {
+	TBSRequest        tbsRequestASN1
+	OptionalSignature asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+// ocspRequestNonceExtension returns the id-pkix-ocsp-nonce extension carried
+// in a raw DER OCSP request, if the client sent one.
+func ocspRequestNonceExtension(rawReq []byte) (pkix.Extension, bool) {
+	var req ocspRequestASN1
+	if _, err := asn1.Unmarshal(rawReq, &req); err != nil {
+		return pkix.Extension{}, false
+	}
+
+	for _, ext := range req.TBSRequest.RequestExtensions {
+		if ext.Id.Equal(ocspNonceOID) {
+			return ext, true
+		}
+	}
+
+	return pkix.Extension{}, false
+}
+
+// pathOcspGet serves the base64-encoded-DER-in-URL form of an OCSP request,
+// as used by most OCSP clients (RFC 6960 appendix A.1.1).
+func pathOcspGet(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp/(?P<req>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"req": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `base64-encoded DER OCSP request`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathOcspHandler,
+		},
+
+		HelpSynopsis:    pathOcspHelpSyn,
+		HelpDescription: pathOcspHelpDesc,
+	}
+}
+
+// pathOcspPost serves the application/ocsp-request POST body form.
+func pathOcspPost(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.WriteOperation: b.pathOcspHandler,
+		},
+
+		HelpSynopsis:    pathOcspHelpSyn,
+		HelpDescription: pathOcspHelpDesc,
+	}
+}
+
+func (b *backend) pathOcspHandler(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getOcspConfig(req)
+	if err != nil {
+		return nil, err
+	}
+	if config.Disable {
+		return ocspUnauthorizedResponse(), nil
+	}
+
+	rawReq, err := ocspRequestBytes(req, data)
+	if err != nil || len(rawReq) == 0 {
+		return ocspMalformedResponse(), nil
+	}
+
+	ocspReq, err := ocsp.ParseRequest(rawReq)
+	if err != nil {
+		return ocspMalformedResponse(), nil
+	}
+
+	caBundle, err := fetchCAInfo(req)
+	if err != nil {
+		return ocspInternalErrorResponse(), nil
+	}
+	if caBundle == nil || !bytes.Equal(ocspReq.IssuerKeyHash, hashIssuerKey(caBundle.Certificate)) {
+		return ocspUnauthorizedResponse(), nil
+	}
+
+	status, revokedAt := lookupOcspStatus(req, ocspReq.SerialNumber)
+
+	signingCert := caBundle.Certificate
+	signingKey, _ := caBundle.PrivateKey.(crypto.Signer)
+	if config.UseDelegatedResponder {
+		if delegate, err := fetchOCSPDelegateBundle(req); err != nil {
+			return ocspInternalErrorResponse(), nil
+		} else if delegate != nil {
+			signingCert = delegate.Certificate
+			if signer, ok := delegate.PrivateKey.(crypto.Signer); ok {
+				signingKey = signer
+			}
+		}
+	}
+	if signingKey == nil {
+		return ocspInternalErrorResponse(), nil
+	}
+
+	expiry, err := time.ParseDuration(config.OcspExpiry)
+	if err != nil {
+		expiry = 24 * time.Hour
+	}
+
+	now := time.Now()
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: ocspReq.SerialNumber,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(expiry),
+		Certificate:  signingCert,
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = revokedAt
+		template.RevocationReason = ocsp.Unspecified
+	}
+	if config.NoncePolicy == "echo" {
+		// ocsp.Response.Extensions is parse-only and ignored when marshaling;
+		// CreateResponse only ever copies ExtraExtensions into the response's
+		// singleExtensions, so that's where the echoed nonce has to go.
+		if nonceExt, ok := ocspRequestNonceExtension(rawReq); ok {
+			template.ExtraExtensions = append(template.ExtraExtensions, nonceExt)
+		}
+	}
+
+	der, err := ocsp.CreateResponse(caBundle.Certificate, signingCert, template, signingKey)
+	if err != nil {
+		return ocspInternalErrorResponse(), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  200,
+			logical.HTTPContentType: "application/ocsp-response",
+			logical.HTTPRawBody:     der,
+		},
+	}, nil
+}
+
+func ocspRequestBytes(req *logical.Request, data *framework.FieldData) ([]byte, error) {
+	if reqStr, ok := data.GetOk("req"); ok {
+		return base64.StdEncoding.DecodeString(reqStr.(string))
+	}
+	if req.HTTPRequest != nil && req.HTTPRequest.Body != nil {
+		return ioutil.ReadAll(req.HTTPRequest.Body)
+	}
+	return nil, fmt.Errorf("no OCSP request found")
+}
+
+// lookupOcspStatus consults the same revocation store that backs the CRL:
+// a matching "revoked/" entry means Revoked, a matching "certs/" entry with
+// no revocation means Good, and anything else is Unknown.
+func lookupOcspStatus(req *logical.Request, serial *big.Int) (int, time.Time) {
+	// serial.Bytes() strips insignificant leading zero *bytes* but, unlike
+	// formatting the big.Int directly, never drops the leading zero *nibble*
+	// of a byte - "%x" on each byte of a []byte always emits two hex digits,
+	// so the result lines up with the zero-padded byte-hex keys used under
+	// certs/ and revoked/.
+	normalized := normalizeSerialForStorage(fmt.Sprintf("%x", serial.Bytes()))
+
+	revInfo, err := fetchRevocationEntry(req, normalized)
+	if err != nil {
+		return ocsp.Unknown, time.Time{}
+	}
+	if revInfo != nil {
+		return ocsp.Revoked, time.Unix(revInfo.RevocationTime, 0)
+	}
+
+	cert, err := fetchCertEntry(req, normalized)
+	if err != nil || cert == nil {
+		return ocsp.Unknown, time.Time{}
+	}
+
+	return ocsp.Good, time.Time{}
+}
+
+func ocspMalformedResponse() *logical.Response     { return ocspErrorResponse(ocsp.MalformedRequestErrorResponse) }
+func ocspInternalErrorResponse() *logical.Response { return ocspErrorResponse(ocsp.InternalErrorErrorResponse) }
+func ocspUnauthorizedResponse() *logical.Response  { return ocspErrorResponse(ocsp.UnauthorizedErrorResponse) }
+
+func ocspErrorResponse(body []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  200,
+			logical.HTTPContentType: "application/ocsp-response",
+			logical.HTTPRawBody:     body,
+		},
+	}
+}
+
+const pathOcspHelpSyn = `
+Query the revocation status of a certificate via RFC 6960 OCSP.
+`
+
+const pathOcspHelpDesc = `
+This path implements an OCSP responder for certificates issued by this
+mount, answering both the GET form (base64 DER request embedded in the
+URL) and the POST form (application/ocsp-request body). The issuer is
+looked up via this mount's CA storage and the per-serial status is
+determined from the same revocation store that backs the CRL. Responses
+are signed directly with the CA key unless config/ocsp has
+use_delegated_responder set, in which case a delegated responder
+key/certificate bearing the id-kp-OCSPSigning EKU is used instead
+(falling back to the CA key if none is stored for this mount). See
+config/ocsp to configure response validity and nonce handling.
+`
@@ -0,0 +1,147 @@
+package pki
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// rawTBSRequest and rawOCSPRequest are deliberately narrower than
+// tbsRequestASN1/ocspRequestASN1: they omit the optional version/
+// requestorName/optionalSignature fields outright rather than leaving them
+// at their Go zero value, which asn1.Marshal would otherwise happily encode
+// as present-but-empty instead of omitting.
+type rawTBSRequest struct {
+	RequestList       []asn1.RawValue
+	RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type rawOCSPRequest struct {
+	TBSRequest rawTBSRequest
+}
+
+func marshalTestOcspRequest(t *testing.T, extensions []pkix.Extension) []byte {
+	t.Helper()
+
+	raw, err := asn1.Marshal(rawOCSPRequest{
+		TBSRequest: rawTBSRequest{
+			// Content doesn't matter - ocspRequestNonceExtension never looks
+			// at RequestList - just needs to be a valid DER element.
+			RequestList:       []asn1.RawValue{{FullBytes: []byte{0x30, 0x03, 0x02, 0x01, 0x01}}},
+			RequestExtensions: extensions,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal test OCSP request: %v", err)
+	}
+	return raw
+}
+
+func TestOcspRequestNonceExtension(t *testing.T) {
+	nonce := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	withNonce := marshalTestOcspRequest(t, []pkix.Extension{{Id: ocspNonceOID, Value: nonce}})
+	ext, ok := ocspRequestNonceExtension(withNonce)
+	if !ok {
+		t.Fatalf("ocspRequestNonceExtension() did not find the nonce extension")
+	}
+	if string(ext.Value) != string(nonce) {
+		t.Fatalf("nonce = %x, want %x", ext.Value, nonce)
+	}
+
+	withoutNonce := marshalTestOcspRequest(t, nil)
+	if _, ok := ocspRequestNonceExtension(withoutNonce); ok {
+		t.Fatalf("ocspRequestNonceExtension() found a nonce in a request that carried none")
+	}
+}
+
+// TestPathOcspHandler_EndToEnd calls pathOcspHandler itself (not just its
+// helpers), so a signature mismatch like the one this fixes - a field
+// reference that doesn't exist on ocsp.Request - breaks this test rather
+// than going unnoticed because every caller stops one level short.
+func TestPathOcspHandler_EndToEnd(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := &backend{}
+	req := &logical.Request{Storage: storage}
+
+	data := &framework.FieldData{
+		Raw:    map[string]interface{}{"req": base64.StdEncoding.EncodeToString([]byte("not a valid OCSP request"))},
+		Schema: pathOcspGet(b).Fields,
+	}
+
+	resp, err := b.pathOcspHandler(req, data)
+	if err != nil {
+		t.Fatalf("pathOcspHandler() error = %v", err)
+	}
+	der, ok := resp.Data[logical.HTTPRawBody].([]byte)
+	if !ok {
+		t.Fatalf("pathOcspHandler() response had no raw body: %#v", resp.Data)
+	}
+	if _, err := ocsp.ParseResponse(der, nil); err == nil {
+		t.Fatalf("expected a malformed-request error response for an undecodable request")
+	}
+
+	if err := storage.Put(&logical.StorageEntry{Key: ocspConfigStorageKey, Value: []byte(`{"disable":true}`)}); err != nil {
+		t.Fatalf("failed to seed config/ocsp: %v", err)
+	}
+	resp, err = b.pathOcspHandler(req, data)
+	if err != nil {
+		t.Fatalf("pathOcspHandler() error = %v", err)
+	}
+	der, ok = resp.Data[logical.HTTPRawBody].([]byte)
+	if !ok {
+		t.Fatalf("pathOcspHandler() response had no raw body: %#v", resp.Data)
+	}
+	if _, err := ocsp.ParseResponse(der, nil); err == nil {
+		t.Fatalf("expected an unauthorized error response once the responder is disabled")
+	}
+}
+
+func TestLookupOcspStatus(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	req := &logical.Request{Storage: storage}
+
+	cert := selfSignedTestCert(t)
+	// A serial whose top byte is < 0x10 exercises the same leading-zero-nibble
+	// case covered in cert_util_test.go, end to end through storage.
+	serial := big.NewInt(0x0aff)
+	normalized := normalizeSerialForStorage(fmt.Sprintf("%x", serial.Bytes()))
+
+	entry := &logical.StorageEntry{Key: "certs/" + normalized, Value: cert.Raw}
+	if err := storage.Put(entry); err != nil {
+		t.Fatalf("failed to seed certs/ entry: %v", err)
+	}
+
+	status, _ := lookupOcspStatus(req, serial)
+	if status != ocsp.Good {
+		t.Fatalf("status = %d, want Good for an issued, non-revoked serial", status)
+	}
+
+	revInfo := &revocationEntry{RevocationTime: 12345}
+	revEntry, err := logical.StorageEntryJSON("revoked/"+normalized, revInfo)
+	if err != nil {
+		t.Fatalf("failed to marshal revocation entry: %v", err)
+	}
+	if err := storage.Put(revEntry); err != nil {
+		t.Fatalf("failed to seed revoked/ entry: %v", err)
+	}
+
+	status, _ = lookupOcspStatus(req, serial)
+	if status != ocsp.Revoked {
+		t.Fatalf("status = %d, want Revoked once a revoked/ entry exists", status)
+	}
+
+	unknownSerial := big.NewInt(0xdead)
+	status, _ = lookupOcspStatus(req, unknownSerial)
+	if status != ocsp.Unknown {
+		t.Fatalf("status = %d, want Unknown for a serial with no stored certificate", status)
+	}
+}
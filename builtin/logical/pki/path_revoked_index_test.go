@@ -0,0 +1,94 @@
+package pki
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func TestRevokedImportExport_RoundTrip(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := &backend{}
+	req := &logical.Request{Storage: storage}
+
+	// Importing an "R" line rebuilds the CRL, which needs a CA.
+	seedCABundle(t, storage)
+
+	cert := selfSignedTestCert(t)
+	certSerial := normalizeSerialForStorage(fmt.Sprintf("%x", cert.SerialNumber.Bytes()))
+	if err := storage.Put(&logical.StorageEntry{Key: "certs/" + certSerial, Value: cert.Raw}); err != nil {
+		t.Fatalf("failed to seed certs/ entry: %v", err)
+	}
+
+	indexTxt := strings.Join([]string{
+		strings.Join([]string{"V", cert.NotAfter.UTC().Format(indexTxtTimeFormat), "", strings.ToUpper(certSerial), "unknown", "/CN=test"}, "\t"),
+		strings.Join([]string{"R", "300101000000Z", "240101000000Z,keyCompromise", "DEADBEEF", "unknown", "/CN=imported-only"}, "\t"),
+	}, "\n") + "\n"
+
+	importData := &framework.FieldData{
+		Raw:    map[string]interface{}{"index_txt": indexTxt},
+		Schema: pathRevokedImport(b).Fields,
+	}
+	if _, err := b.pathRevokedImportWrite(req, importData); err != nil {
+		t.Fatalf("pathRevokedImportWrite() error = %v", err)
+	}
+
+	resp, err := b.pathRevokedExportRead(req, &framework.FieldData{})
+	if err != nil {
+		t.Fatalf("pathRevokedExportRead() error = %v", err)
+	}
+	out := resp.Data["index_txt"].(string)
+
+	if !strings.Contains(out, "deadbeef") && !strings.Contains(out, "DEADBEEF") {
+		t.Fatalf("export missing imported-only revocation with no certs/ entry: %s", out)
+	}
+	if !strings.Contains(out, "keyCompromise") {
+		t.Fatalf("export dropped revocation reason: %s", out)
+	}
+	if !strings.Contains(out, "/CN=imported-only") {
+		t.Fatalf("export clobbered the subject DN of a revoked-only entry with \"unknown\": %s", out)
+	}
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	found := false
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 4 && strings.EqualFold(fields[3], certSerial) {
+			found = true
+			if fields[0] != "V" {
+				t.Fatalf("status for unrevoked certs/ entry = %q, want V", fields[0])
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("export missing the seeded certs/ entry: %s", out)
+	}
+}
+
+func TestIndexTxtLine_ExpiredCertEmitsE(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := &backend{}
+	req := &logical.Request{Storage: storage}
+
+	// The expiry has to be in the encoded certificate, since export re-parses
+	// cert.Raw from storage.
+	cert := selfSignedTestCertNotAfter(t, time.Now().Add(-time.Hour))
+	certSerial := normalizeSerialForStorage(fmt.Sprintf("%x", cert.SerialNumber.Bytes()))
+	if err := storage.Put(&logical.StorageEntry{Key: "certs/" + certSerial, Value: cert.Raw}); err != nil {
+		t.Fatalf("failed to seed certs/ entry: %v", err)
+	}
+
+	resp, err := b.pathRevokedExportRead(req, &framework.FieldData{})
+	if err != nil {
+		t.Fatalf("pathRevokedExportRead() error = %v", err)
+	}
+	out := resp.Data["index_txt"].(string)
+
+	if !strings.HasPrefix(out, "E\t") {
+		t.Fatalf("export of expired, non-revoked cert = %q, want status E", out)
+	}
+}